@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/influxdata/toml"
+
+	"github.com/srebhan/test/migrations"
+)
+
+// stringSliceFlag implements flag.Value for options that may be given
+// multiple times, such as -config-directory.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprint([]string(*s))
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// collectFiles expands -config-directory directories (recursively, every
+// *.conf file) and the positional arguments (each treated as a glob
+// pattern, falling back to a literal path when it doesn't match anything)
+// into a flat, de-duplicated, sorted list of files to migrate. The returned
+// dirOf map records, for each file, which -config-directory it was
+// discovered under ("" for files that came from a positional argument), so
+// a later parse failure can be scoped to siblings from the same directory
+// instead of being merged with unrelated files.
+func collectFiles(dirs []string, patterns []string) (files []string, dirOf map[string]string, err error) {
+	seen := make(map[string]bool)
+	dirOf = make(map[string]string)
+	add := func(path, dir string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+			dirOf[path] = dir
+		}
+	}
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(path) != ".conf" {
+				return nil
+			}
+			add(path, dir)
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("walking %q failed: %w", dir, err)
+		}
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			// Not a glob pattern, or a pattern that matched nothing: treat
+			// it as a literal path so a plain filename still works.
+			add(pattern, "")
+			continue
+		}
+		for _, match := range matches {
+			add(match, "")
+		}
+	}
+
+	sort.Strings(files)
+	return files, dirOf, nil
+}
+
+// fileBoundary records where a source file's content starts within a
+// document assembled by mergeFiles, in lines, so sections split across
+// several files can be routed back to the file they came from.
+type fileBoundary struct {
+	file      string
+	startLine int // 1-based line of this file's first line in the merged document
+	lineCount int
+}
+
+// mergeFiles concatenates the named files, in order, into one buffer and
+// returns it together with each file's line boundary within that buffer.
+func mergeFiles(files []string) ([]byte, []fileBoundary, error) {
+	var merged bytes.Buffer
+	bounds := make([]fileBoundary, 0, len(files))
+
+	line := 1
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening %q failed: %w", file, err)
+		}
+
+		merged.Write(data)
+		lineCount := bytes.Count(data, []byte("\n"))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			merged.WriteByte('\n')
+			lineCount++
+		}
+
+		bounds = append(bounds, fileBoundary{file: file, startLine: line, lineCount: lineCount})
+		line += lineCount
+	}
+
+	return merged.Bytes(), bounds, nil
+}
+
+// fileForLine returns the file owning the merged-document line number,
+// defaulting to the first file if line falls before any known boundary
+// (e.g. the synthetic "header" section).
+func fileForLine(bounds []fileBoundary, line int) string {
+	owner := bounds[0].file
+	for _, b := range bounds {
+		if line < b.startLine {
+			break
+		}
+		owner = b.file
+	}
+	return owner
+}
+
+// migrateMerged handles files whose TOML could not be parsed on their own,
+// typically because one plugin's configuration is split across several
+// files under the same -config-directory. It re-parses them as a single
+// concatenated document and, once migrated, writes each resulting section
+// back to the file that originally contained its `[[category.plugin]]`
+// header line.
+func migrateMerged(opts runOptions, files []string) (bool, error) {
+	merged, bounds, err := mergeFiles(files)
+	if err != nil {
+		return false, err
+	}
+
+	substituted, placeholders := substituteRefs(merged)
+	ctx := migrations.NewMigrationContext(placeholders)
+
+	root, err := toml.Parse(substituted)
+	if err != nil {
+		return false, fmt.Errorf("parsing merged configuration failed: %w", err)
+	}
+
+	sections, err := splitToSections(root)
+	if err != nil {
+		return false, fmt.Errorf("splitting merged configuration failed: %w", err)
+	}
+	if len(sections) == 0 {
+		return false, fmt.Errorf("no TOML configuration found across %v", files)
+	}
+	assignTextToSections(substituted, sections)
+
+	if opts.check {
+		// Batch sections by origin file, the same way the single-file path
+		// reports once per file: reportCandidates renders one report
+		// document per call, and calling it per section would, for
+		// -report-format=json/sarif, print one (possibly "null") document
+		// per section instead of one parseable document per file.
+		byFile := make(map[string][]section)
+		for _, s := range sections {
+			origin := fileForLine(bounds, s.begin)
+			byFile[origin] = append(byFile[origin], s)
+		}
+		for _, file := range files {
+			owned := byFile[file]
+			if len(owned) == 0 {
+				// Nothing assigned to this file (its content was absorbed
+				// into a section declared in another file in the group);
+				// there is nothing to report for it.
+				continue
+			}
+			if err := reportCandidates(opts, file, owned); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	// Merged line numbers don't mean much to the caller, so resolve the
+	// report's File field back to the real origin file per section.
+	sections, report := migrateSections(sections, "", opts.debug, ctx)
+	for i, e := range report.Entries {
+		report.Entries[i].File = fileForLine(bounds, e.LineStart)
+	}
+
+	grouped := make(map[string][]section)
+	for _, s := range sections {
+		origin := fileForLine(bounds, s.begin)
+		grouped[origin] = append(grouped[origin], s)
+	}
+
+	var changed bool
+	for _, file := range files {
+		owned := grouped[file]
+		if len(owned) == 0 {
+			// This file's entire content was absorbed into a section whose
+			// header lives in another file in the group - the normal
+			// outcome when a plugin's sub-tables are split across files.
+			// Leave it untouched instead of overwriting it with an empty
+			// document.
+			if opts.debug {
+				log.Printf("%s: no sections assigned, leaving file untouched", file)
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return changed, fmt.Errorf("opening %q failed: %w", file, err)
+		}
+
+		fileChanged, err := emitResult(opts, file, data, owned, fileReport(report, file), ctx)
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || fileChanged
+	}
+	return changed, nil
+}
+
+// fileReport returns the subset of report entries belonging to file.
+func fileReport(report MigrationReport, file string) MigrationReport {
+	var filtered MigrationReport
+	for _, e := range report.Entries {
+		if e.File == file {
+			filtered.Add(e)
+		}
+	}
+	return filtered
+}
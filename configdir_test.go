@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeFilesLineCounting(t *testing.T) {
+	dir := t.TempDir()
+
+	// a.conf deliberately has no trailing newline so mergeFiles has to
+	// account for the synthetic '\n' it appends before counting b.conf's
+	// start line.
+	files := []string{
+		filepath.Join(dir, "a.conf"),
+		filepath.Join(dir, "b.conf"),
+		filepath.Join(dir, "c.conf"),
+	}
+	contents := []string{
+		"[[inputs.cpu]]\n[[inputs.disk]]", // lines 1-2, no trailing newline
+		"[[inputs.mem]]\n",                // line 3
+		"[[inputs.net]]\n",                // line 4
+	}
+	for i, file := range files {
+		if err := os.WriteFile(file, []byte(contents[i]), 0644); err != nil {
+			t.Fatalf("writing %q failed: %v", file, err)
+		}
+	}
+
+	_, bounds, err := mergeFiles(files)
+	if err != nil {
+		t.Fatalf("mergeFiles failed: %v", err)
+	}
+
+	want := []fileBoundary{
+		{file: files[0], startLine: 1, lineCount: 2},
+		{file: files[1], startLine: 3, lineCount: 1},
+		{file: files[2], startLine: 4, lineCount: 1},
+	}
+	for i, w := range want {
+		if bounds[i] != w {
+			t.Fatalf("bounds[%d] = %+v, want %+v", i, bounds[i], w)
+		}
+	}
+
+	// Line 2 (the "disk" header) must still be attributed to a.conf, not
+	// carried over into b.conf by an off-by-one in the accumulated line
+	// count.
+	if got := fileForLine(bounds, 2); got != files[0] {
+		t.Errorf("fileForLine(2) = %q, want %q", got, files[0])
+	}
+	if got := fileForLine(bounds, 3); got != files[1] {
+		t.Errorf("fileForLine(3) = %q, want %q", got, files[1])
+	}
+	if got := fileForLine(bounds, 4); got != files[2] {
+		t.Errorf("fileForLine(4) = %q, want %q", got, files[2])
+	}
+}
+
+func TestFileForLineBeforeFirstBoundary(t *testing.T) {
+	bounds := []fileBoundary{
+		{file: "a.conf", startLine: 1, lineCount: 3},
+		{file: "b.conf", startLine: 4, lineCount: 2},
+	}
+	if got := fileForLine(bounds, 0); got != "a.conf" {
+		t.Errorf("fileForLine(0) = %q, want %q", got, "a.conf")
+	}
+}
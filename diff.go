@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unifiedDiff renders a minimal unified diff between a and b, labeling the
+// two sides with aLabel/bLabel. It is line-based and emits the whole file as
+// a single hunk, with every unchanged line as context rather than only the
+// lines around each change; for the size of configuration files this tool
+// deals with, a plain longest-common-subsequence diff is fast enough and
+// easy to read.
+func unifiedDiff(aLabel, bLabel string, a, b []byte) string {
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+
+	ops := diffLines(aLines, bLines)
+	if len(ops) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n", aLabel)
+	fmt.Fprintf(&out, "+++ %s\n", bLabel)
+	aStart, bStart := 1, 1
+	if len(aLines) == 0 {
+		aStart = 0
+	}
+	if len(bLines) == 0 {
+		bStart = 0
+	}
+	fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, len(aLines), bStart, len(bLines))
+	for _, op := range ops {
+		switch op.kind {
+		case diffEqual:
+			fmt.Fprintf(&out, " %s\n", op.line)
+		case diffRemove:
+			fmt.Fprintf(&out, "-%s\n", op.line)
+		case diffAdd:
+			fmt.Fprintf(&out, "+%s\n", op.line)
+		}
+	}
+	return out.String()
+}
+
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+type diffKind int
+
+const (
+	diffEqual diffKind = iota
+	diffRemove
+	diffAdd
+)
+
+type diffOp struct {
+	kind diffKind
+	line string
+}
+
+// diffLines computes a line-level diff between a and b using the classic
+// longest-common-subsequence backtrack.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{diffEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{diffRemove, a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{diffAdd, b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{diffRemove, a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{diffAdd, b[j]})
+	}
+
+	changed := false
+	for _, op := range ops {
+		if op.kind != diffEqual {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return ops
+}
@@ -0,0 +1,94 @@
+package main
+
+import "testing"
+
+func TestDiffLinesNoChange(t *testing.T) {
+	a := []string{"one", "two", "three"}
+	b := []string{"one", "two", "three"}
+	if ops := diffLines(a, b); ops != nil {
+		t.Errorf("diffLines(equal) = %v, want nil", ops)
+	}
+}
+
+func TestDiffLinesAppend(t *testing.T) {
+	a := []string{"one", "two"}
+	b := []string{"one", "two", "three"}
+	got := diffLines(a, b)
+	want := []diffOp{
+		{diffEqual, "one"},
+		{diffEqual, "two"},
+		{diffAdd, "three"},
+	}
+	assertOpsEqual(t, got, want)
+}
+
+func TestDiffLinesRemove(t *testing.T) {
+	a := []string{"one", "two", "three"}
+	b := []string{"one", "three"}
+	got := diffLines(a, b)
+	want := []diffOp{
+		{diffEqual, "one"},
+		{diffRemove, "two"},
+		{diffEqual, "three"},
+	}
+	assertOpsEqual(t, got, want)
+}
+
+func TestDiffLinesReplace(t *testing.T) {
+	a := []string{"one", "two", "three"}
+	b := []string{"one", "TWO", "three"}
+	got := diffLines(a, b)
+	want := []diffOp{
+		{diffEqual, "one"},
+		{diffRemove, "two"},
+		{diffAdd, "TWO"},
+		{diffEqual, "three"},
+	}
+	assertOpsEqual(t, got, want)
+}
+
+func TestDiffLinesEmptySides(t *testing.T) {
+	if got := diffLines(nil, nil); got != nil {
+		t.Errorf("diffLines(nil, nil) = %v, want nil", got)
+	}
+
+	got := diffLines(nil, []string{"one"})
+	want := []diffOp{{diffAdd, "one"}}
+	assertOpsEqual(t, got, want)
+
+	got = diffLines([]string{"one"}, nil)
+	want = []diffOp{{diffRemove, "one"}}
+	assertOpsEqual(t, got, want)
+}
+
+func TestUnifiedDiffNoChange(t *testing.T) {
+	data := []byte("[[inputs.cpu]]\n")
+	if d := unifiedDiff("a", "b", data, data); d != "" {
+		t.Errorf("unifiedDiff(equal) = %q, want empty", d)
+	}
+}
+
+func TestUnifiedDiffHeaders(t *testing.T) {
+	a := []byte("[[inputs.cpu]]\n")
+	b := []byte("[[inputs.mem]]\n")
+	d := unifiedDiff("a.conf", "a.conf.migrated", a, b)
+	if d == "" {
+		t.Fatal("unifiedDiff(changed) = empty, want a diff")
+	}
+	wantPrefix := "--- a.conf\n+++ a.conf.migrated\n"
+	if d[:len(wantPrefix)] != wantPrefix {
+		t.Errorf("unifiedDiff headers = %q, want prefix %q", d, wantPrefix)
+	}
+}
+
+func assertOpsEqual(t *testing.T, got, want []diffOp) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("diffLines = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("diffLines[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
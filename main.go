@@ -25,50 +25,105 @@ type section struct {
 	raw     *bytes.Buffer
 }
 
-func splitToSections(root *ast.Table) []section {
+// legacyCategoryAliases maps umbrella table names used by early Telegraf
+// releases (the 0.2.x/0.3.x era) to the modern plugin category they were
+// superseded by, e.g. `[[plugins.cpu]]` became `[[inputs.cpu]]`.
+var legacyCategoryAliases = map[string]string{
+	"plugins": "inputs",
+}
+
+// explodeCategory turns a `[category.plugin]` table-of-lists (elements must
+// be a *ast.Table whose fields are each a []*ast.Table) into one section per
+// plugin instance, named "prefix.plugin". It returns an error, rather than
+// aborting the program, if elements doesn't have that shape: a standalone
+// file containing only a plugin's nested sub-table (e.g.
+// `[inputs.kafka_consumer.sasl]` with no `[[inputs.kafka_consumer]]`
+// header) is valid TOML with exactly this wrong shape, and the caller
+// treats it the same as a file that failed to parse at all.
+func explodeCategory(name, prefix string, elements interface{}) ([]section, error) {
+	category, ok := elements.(*ast.Table)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a table (%T)", name, elements)
+	}
+
+	var sections []section
+	for plugin, elements := range category.Fields {
+		tbls, ok := elements.([]*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("elements of \"%s.%s\" is not a list of tables (%T)", name, plugin, elements)
+		}
+		for _, tbl := range tbls {
+			sections = append(sections, section{
+				name:    prefix + "." + tbl.Name,
+				begin:   tbl.Line,
+				content: tbl,
+				raw:     &bytes.Buffer{},
+			})
+		}
+	}
+	return sections, nil
+}
+
+// splitToSections walks root's top-level tables and explodes each plugin
+// category into sections. It returns an error, instead of aborting the
+// program, whenever a table doesn't have the shape a category or legacy
+// bare-plugin table is expected to have, so a caller reading a single file
+// in isolation can fall back to a merged pass across its siblings (see
+// migrateMerged) instead of crashing the whole run.
+func splitToSections(root *ast.Table) ([]section, error) {
 	var sections []section
 	for name, elements := range root.Fields {
-		switch name {
-		case "inputs", "outputs", "processors", "aggregators":
-			category, ok := elements.(*ast.Table)
-			if !ok {
-				log.Fatalf("%q is not a table (%T)", name, category)
+		switch {
+		case name == "inputs" || name == "outputs" || name == "processors" || name == "aggregators":
+			exploded, err := explodeCategory(name, name, elements)
+			if err != nil {
+				return nil, err
 			}
-
-			for plugin, elements := range category.Fields {
-				tbls, ok := elements.([]*ast.Table)
-				if !ok {
-					log.Fatalf("elements of \"%s.%s\" is not a list of tables (%T)", name, plugin, elements)
-				}
-				for _, tbl := range tbls {
-					s := section{
-						name:    name + "." + tbl.Name,
-						begin:   tbl.Line,
-						content: tbl,
-						raw:     &bytes.Buffer{},
-					}
-					sections = append(sections, s)
-				}
+			sections = append(sections, exploded...)
+		case legacyCategoryAliases[name] != "":
+			// Legacy umbrella table, e.g. `[[plugins.cpu]]` -> `[[inputs.cpu]]`.
+			exploded, err := explodeCategory(name, legacyCategoryAliases[name], elements)
+			if err != nil {
+				return nil, err
 			}
+			sections = append(sections, exploded...)
 		default:
 			tbl, ok := elements.(*ast.Table)
 			if !ok {
-				log.Fatalf("%q is not a table (%T)", name, elements)
+				return nil, fmt.Errorf("%q is not a table (%T)", name, elements)
+			}
+
+			// A bare plugin table from before plugins lived under a
+			// category, e.g. `[cpu]` instead of `[[inputs.cpu]]`. Only
+			// rewrite names that are actually known plugins so genuine
+			// top-level sections such as `[agent]`/`[global_tags]` are
+			// left untouched.
+			sectionName := name
+			switch {
+			case migrations.AmbiguousPlugins[name]:
+				// Real Telegraf has plugins with the same bare name in
+				// more than one category (e.g. "file", "exec", "http" in
+				// both inputs and outputs); guessing which one a legacy
+				// bare table meant risks silently misclassifying it, so
+				// leave it unrewritten and flag it instead.
+				log.Printf("warning: %q is ambiguous across plugin categories, leaving legacy table [%s] unrewritten", name, name)
+			case migrations.KnownPlugins[name] != "":
+				sectionName = migrations.KnownPlugins[name] + "." + name
 			}
-			s := section{
-				name:    name,
+
+			sections = append(sections, section{
+				name:    sectionName,
 				begin:   tbl.Line,
 				content: tbl,
 				raw:     &bytes.Buffer{},
-			}
-			sections = append(sections, s)
+			})
 		}
 	}
 
 	// Sort the TOML elements by begin (line-number)
 	sort.SliceStable(sections, func(i, j int) bool { return sections[i].begin < sections[j].begin })
 
-	return sections
+	return sections, nil
 }
 
 func assignTextToSections(data []byte, sections []section) {
@@ -131,6 +186,78 @@ func assignTextToSections(data []byte, sections []section) {
 	}
 }
 
+// applyOptionMigrations runs every option migration registered for the
+// given plugin section against tbl, returning whether any of them modified
+// the table.
+func applyOptionMigrations(plugin string, tbl *ast.Table, ctx *migrations.MigrationContext) (bool, error) {
+	var changed bool
+	prefix := plugin + "/"
+	for key, migrate := range migrations.OptionMigrations {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		ok, err := migrate(tbl, ctx)
+		if err != nil {
+			return changed, fmt.Errorf("option %q: %w", strings.TrimPrefix(key, prefix), err)
+		}
+		changed = changed || ok
+	}
+	return changed, nil
+}
+
+// renderTable patches original - the section's raw text as captured by
+// assignTextToSections, before any migration ran - replacing only the
+// header line and whichever top-level key/value lines tbl's fields
+// currently hold, leaving every other line (standalone comments, nested
+// tables, tag tables, arrays of sub-tables) exactly as written. It is used
+// to emit a section whose raw text was never replaced by a whole-plugin
+// migration, but whose options were rewritten in place by one or more
+// option migrations: re-serializing the whole section from tbl.Fields
+// would otherwise discard anything assignTextToSections doesn't represent
+// as a flat key/value, and option migrations are common enough (they run
+// for every section, not just ones with a dedicated plugin migration) that
+// this is the common path, not an edge case.
+//
+// arrayOfTables selects the header style: every plugin section this tool
+// handles is declared as an array-of-tables (`[[inputs.cpu]]`), so callers
+// pass true whenever name is a "category.plugin" section; bare top-level
+// tables such as `[agent]` pass false.
+func renderTable(name string, arrayOfTables bool, tbl *ast.Table, original []byte) []byte {
+	lines := bytes.Split(original, []byte("\n"))
+
+	// assignTextToSections attaches comment lines directly in front of the
+	// header to this section, so the header is the first non-comment line.
+	hdr := 0
+	for hdr < len(lines)-1 && bytes.HasPrefix(bytes.TrimSpace(lines[hdr]), []byte("#")) {
+		hdr++
+	}
+
+	header := fmt.Sprintf("[%s]", name)
+	if arrayOfTables {
+		header = fmt.Sprintf("[[%s]]", name)
+	}
+	lines[hdr] = []byte(header)
+
+	for _, field := range tbl.Fields {
+		kv, ok := field.(*ast.KeyValue)
+		if !ok {
+			continue
+		}
+
+		line := []byte(fmt.Sprintf("  %s = %s", kv.Key, kv.Value.Source()))
+		if idx := hdr + kv.Line - tbl.Line; idx > hdr && idx < len(lines) {
+			lines[idx] = line
+			continue
+		}
+		// kv.Line doesn't land inside this section's raw text (e.g. a
+		// field an option migration added outright rather than one parsed
+		// from source); append it rather than dropping it.
+		lines = append(lines, line)
+	}
+
+	return bytes.Join(lines, []byte("\n"))
+}
+
 func Usage() {
 	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <config file(s)>\n", os.Args[0])
 	fmt.Fprint(flag.CommandLine.Output(), `
@@ -146,13 +273,33 @@ func Usage() {
 	flag.PrintDefaults()
 }
 
+// runOptions collects the command-line knobs that affect how a parsed
+// configuration is migrated and written back out, so they can be threaded
+// through the single-file and merged-directory code paths alike.
+type runOptions struct {
+	debug        bool
+	check        bool
+	diff         bool
+	inplace      bool
+	output       string
+	reportFormat string
+}
+
 func main() {
 	flag.Usage = Usage
 
 	// Define options
-	var debug, help bool
-	flag.BoolVar(&debug, "debug", false, "print debugging information")
+	var opts runOptions
+	var help bool
+	var configDirs stringSliceFlag
+	flag.BoolVar(&opts.debug, "debug", false, "print debugging information")
 	flag.BoolVar(&help, "help", false, "print this help text")
+	flag.BoolVar(&opts.check, "check", false, "only report which plugins and options would be migrated, without changing anything")
+	flag.BoolVar(&opts.diff, "diff", false, "print a unified diff of the migration to stdout and exit with a non-zero status if changes would be made; implies a dry-run, no files are written")
+	flag.BoolVar(&opts.inplace, "inplace", false, "overwrite the input file with the migrated configuration after writing a <file>.bak backup")
+	flag.StringVar(&opts.output, "output", "", "write the migrated configuration to this path instead of <file>.migrated (only valid with a single input file)")
+	flag.StringVar(&opts.reportFormat, "report-format", "text", "format of the migration report: text, json or sarif")
+	flag.Var(&configDirs, "config-directory", "recursively migrate every *.conf file in this directory; can be given multiple times")
 	flag.Parse()
 
 	if help {
@@ -160,78 +307,283 @@ func main() {
 		os.Exit(0)
 	}
 
-	if flag.NArg() == 0 {
+	files, fileDir, err := collectFiles(configDirs, flag.Args())
+	if err != nil {
+		log.Fatalf("collecting input files failed: %v", err)
+	}
+	if len(files) == 0 {
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	for _, filename := range flag.Args() {
-		// Read and parse the config file
+	if opts.inplace && opts.output != "" {
+		log.Fatalln("-inplace and -output are mutually exclusive")
+	}
+	if opts.output != "" && len(files) > 1 {
+		log.Fatalln("-output can only be used with a single input file")
+	}
+	switch opts.reportFormat {
+	case "text", "json", "sarif":
+	default:
+		log.Fatalf("unknown -report-format %q: must be text, json or sarif", opts.reportFormat)
+	}
+
+	var changesFound bool
+	unparsedByDir := make(map[string][]string)
+	for _, filename := range files {
 		data, err := os.ReadFile(filename)
 		if err != nil {
 			log.Fatalf("Opening %q failed: %v", filename, err)
 		}
 
-		root, err := toml.Parse(data)
-		if err != nil {
+		// Substitute $VAR/${VAR}/@{secretstore:key} references with
+		// sentinel placeholders before parsing, so that migrations which
+		// round-trip a value through toml.Parse can't accidentally bake in
+		// a resolved (or empty) value in place of the literal reference.
+		substituted, placeholders := substituteRefs(data)
+		ctx := migrations.NewMigrationContext(placeholders)
+
+		root, parseErr := toml.Parse(substituted)
+		var sections []section
+		if parseErr == nil {
+			sections, err = splitToSections(root)
+		}
+		if parseErr != nil || err != nil {
+			// The file may be a fragment of a plugin whose other
+			// sub-tables live in a sibling file under the same
+			// -config-directory (common when a config is split across
+			// several files). This fails in one of two ways: toml.Parse
+			// itself can reject it outright, or - the case this tool must
+			// also support - a file containing only a nested sub-table
+			// (e.g. `[inputs.kafka_consumer.sasl]` with no
+			// `[[inputs.kafka_consumer]]` header) is valid standalone TOML
+			// that splitToSections can't explode into a section. Either
+			// way, defer it to the merged pass below instead of failing
+			// outright. A bare positional argument has no sibling
+			// directory to merge against, so it fails immediately with a
+			// clear parse error instead of silently joining an unrelated
+			// directory's merge.
+			if dir := fileDir[filename]; dir != "" {
+				unparsedByDir[dir] = append(unparsedByDir[dir], filename)
+				continue
+			}
+			if parseErr != nil {
+				log.Fatalf("Parsing %q failed: %v", filename, parseErr)
+			}
 			log.Fatalf("Parsing %q failed: %v", filename, err)
 		}
 
-		// Split the configuration into sections containing the location
-		// in the file.
-		sections := splitToSections(root)
 		if len(sections) == 0 {
 			log.Fatalln("no TOML configuration found")
 		}
+		assignTextToSections(substituted, sections)
 
-		// Assign the configuration text to the corresponding segments
-		assignTextToSections(data, sections)
+		changed, err := processConfig(opts, filename, data, sections, ctx)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		changesFound = changesFound || changed
+	}
 
-		// Do the actual migration(s)
-		for idx, s := range sections {
-			migrate, found := migrations.PluginMigrations[s.name]
-			if !found {
-				continue
-			}
+	dirs := make([]string, 0, len(unparsedByDir))
+	for dir := range unparsedByDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		group := unparsedByDir[dir]
+		changed, err := migrateMerged(opts, group)
+		if err != nil {
+			log.Fatalf("merged migration of %v failed: %v", group, err)
+		}
+		changesFound = changesFound || changed
+	}
+
+	if opts.diff && changesFound {
+		os.Exit(1)
+	}
+}
+
+// processConfig runs the check/migrate/diff/write pipeline for a single
+// already-parsed file and reports whether it would or did change. data must
+// be the file's original, unsubstituted bytes; ctx carries the placeholders
+// substituted for $VAR/@{secretstore:key} references before parsing, so
+// they can be restored in the emitted output.
+func processConfig(opts runOptions, filename string, data []byte, sections []section, ctx *migrations.MigrationContext) (bool, error) {
+	if opts.check {
+		if err := reportCandidates(opts, filename, sections); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	sections, report := migrateSections(sections, filename, opts.debug, ctx)
+	return emitResult(opts, filename, data, sections, report, ctx)
+}
+
+// emitResult renders the migration report, then either prints a diff or
+// writes the migrated sections to disk, depending on opts. It returns
+// whether the migrated content differs from data.
+func emitResult(opts runOptions, filename string, data []byte, sections []section, report MigrationReport, ctx *migrations.MigrationContext) (bool, error) {
+	rendered, err := report.Render(opts.reportFormat)
+	if err != nil {
+		return false, fmt.Errorf("rendering report for %q failed: %w", filename, err)
+	}
+	if rendered != "" {
+		// The report is progress/diagnostic output, not the tool's primary
+		// result, so it goes to stderr: -diff's stdout must stay a clean,
+		// parseable diff, and a plain run's stdout must stay clean too.
+		fmt.Fprint(os.Stderr, rendered)
+	}
+
+	var assembled bytes.Buffer
+	for _, s := range sections {
+		if _, err := s.raw.WriteTo(&assembled); err != nil {
+			return false, fmt.Errorf("assembling migrated %q failed: %w", filename, err)
+		}
+	}
+	migrated := bytes.NewBuffer(restoreRefs(assembled.Bytes(), ctx))
+
+	if opts.diff {
+		d := unifiedDiff(filename, filename+".migrated", data, migrated.Bytes())
+		if d != "" {
+			fmt.Print(d)
+		}
+		return d != "", nil
+	}
 
-			log.Printf("Migrating plugin %q in line %d...", s.name, s.begin)
+	outfn := filename + ".migrated"
+	switch {
+	case opts.inplace:
+		backupfn := filename + ".bak"
+		if err := os.WriteFile(backupfn, data, 0644); err != nil {
+			return false, fmt.Errorf("writing backup %q failed: %w", backupfn, err)
+		}
+		outfn = filename
+	case opts.output != "":
+		outfn = opts.output
+	}
+
+	if err := os.WriteFile(outfn, migrated.Bytes(), 0644); err != nil {
+		return false, fmt.Errorf("writing to %q failed: %w", outfn, err)
+	}
+	// assignTextToSections always terminates the last section's raw text
+	// with a newline, even when the source file had none, so a trailing
+	// newline by itself must not count as a change; trim it from both
+	// sides before comparing, the same normalization unifiedDiff's line
+	// splitting already applies.
+	return !bytes.Equal(bytes.TrimSuffix(data, []byte("\n")), bytes.TrimSuffix(migrated.Bytes(), []byte("\n"))), nil
+}
+
+// migrateSections runs the plugin- and option-level migrations over
+// sections, accumulating a MigrationReport instead of logging each step as
+// it happens. It returns the updated sections (their raw buffers replaced
+// where a migration applied) and the report describing what happened.
+func migrateSections(sections []section, filename string, debug bool, ctx *migrations.MigrationContext) ([]section, MigrationReport) {
+	var report MigrationReport
+	for idx, s := range sections {
+		original := s.raw.Bytes()
+		entry := MigrationReportEntry{
+			File:        filename,
+			Plugin:      s.name,
+			LineStart:   s.begin,
+			LineEnd:     s.begin + bytes.Count(original, []byte("\n")),
+			BytesBefore: len(original),
+		}
+
+		// Option migrations run first, for every section, independent of
+		// whether a whole-plugin migration exists, so common cross-plugin
+		// deprecations can be handled without a dedicated plugin migration
+		// function. They mutate s.content in place, so a whole-plugin
+		// migration that runs afterwards sees their effect rather than
+		// having it discarded.
+		changed, err := applyOptionMigrations(s.name, s.content, ctx)
+		if err != nil {
+			log.Fatalf("migrating options of %q (line %d) failed: %v", s.name, s.begin, err)
+		}
+
+		var migrated bool
+		if migrateV2, found := migrations.PluginMigrationsV2[s.name]; found {
+			result, err := migrateV2(s.content, ctx)
+			if err != nil {
+				log.Fatalf("migrating %q (line %d) failed: %v", s.name, s.begin, err)
+			}
+			s.raw = bytes.NewBuffer(result.Content)
+			entry.Diagnostics = result.Diagnostics
+			migrated = true
+		} else if migrate, found := migrations.PluginMigrations[s.name]; found {
 			result, err := migrate(s.content)
 			if err != nil {
 				log.Fatalf("migrating %q (line %d) failed: %v", s.name, s.begin, err)
 			}
 			s.raw = bytes.NewBuffer(result)
-			sections[idx] = s
-
-			if debug {
-				fmt.Println("=================================================")
-				fmt.Println(s.name)
-				fmt.Println("-------------------------------------------------")
-				fmt.Println(s.raw.String())
-				fmt.Println("-------------------------------------------------")
-				for k, content := range s.content.Fields {
-					fmt.Printf("%s: %v (%T)\n", k, content, content)
-					switch v := content.(type) {
-					case *ast.KeyValue:
-						fmt.Printf("  -> %s: %v (%T)\n", v.Key, v.Value, v.Value)
-					}
+			migrated = true
+		} else if changed {
+			s.raw = bytes.NewBuffer(renderTable(s.name, strings.Contains(s.name, "."), s.content, original))
+			migrated = true
+		}
+		sections[idx] = s
+
+		entry.Applied = migrated
+		entry.BytesAfter = s.raw.Len()
+		report.Add(entry)
+
+		if debug {
+			fmt.Println("=================================================")
+			fmt.Println(s.name)
+			fmt.Println("-------------------------------------------------")
+			fmt.Println(s.raw.String())
+			fmt.Println("-------------------------------------------------")
+			for k, content := range s.content.Fields {
+				fmt.Printf("%s: %v (%T)\n", k, content, content)
+				switch v := content.(type) {
+				case *ast.KeyValue:
+					fmt.Printf("  -> %s: %v (%T)\n", v.Key, v.Value, v.Value)
 				}
-				fmt.Println("=================================================")
 			}
+			fmt.Println("=================================================")
 		}
+	}
+	return sections, report
+}
 
-		// Write the output file
-		outfn := filename + ".migrated"
-		file, err := os.OpenFile(outfn, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-		if err != nil {
-			log.Fatalf("Opening %q failed: %v", outfn, err)
+// reportCandidates reports, without migrating anything, which plugins and
+// options in sections would be migrated, honoring opts.reportFormat the
+// same way a real migration run would.
+func reportCandidates(opts runOptions, filename string, sections []section) error {
+	var report MigrationReport
+	for _, s := range sections {
+		var diags []migrations.Diagnostic
+
+		_, v1 := migrations.PluginMigrations[s.name]
+		_, v2 := migrations.PluginMigrationsV2[s.name]
+		if v1 || v2 {
+			diags = append(diags, migrations.Diagnostic{Severity: "candidate", Message: fmt.Sprintf("plugin %q would be migrated", s.name)})
 		}
-		defer file.Close()
 
-		for _, s := range sections {
-			_, err = s.raw.WriteTo(file)
-			if err != nil {
-				log.Fatalf("Writing to %q failed: %v", outfn, err)
+		prefix := s.name + "/"
+		for key := range migrations.OptionMigrations {
+			if strings.HasPrefix(key, prefix) {
+				diags = append(diags, migrations.Diagnostic{Severity: "candidate", Message: fmt.Sprintf("option %q of %q would be migrated", strings.TrimPrefix(key, prefix), s.name)})
 			}
 		}
+
+		if len(diags) == 0 {
+			continue
+		}
+		report.Add(MigrationReportEntry{
+			File:        filename,
+			Plugin:      s.name,
+			LineStart:   s.begin,
+			LineEnd:     s.begin + bytes.Count(s.raw.Bytes(), []byte("\n")),
+			Diagnostics: diags,
+		})
+	}
+
+	rendered, err := report.Render(opts.reportFormat)
+	if err != nil {
+		return fmt.Errorf("rendering report for %q failed: %w", filename, err)
 	}
+	fmt.Print(rendered)
+	return nil
 }
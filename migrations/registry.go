@@ -2,19 +2,163 @@ package migrations
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/influxdata/toml/ast"
 )
 
 type PluginMigrationFunc func(*ast.Table) ([]byte, error)
 
+// MigrationContext carries run-wide state into migration functions. Right
+// now that state is the set of $VAR/${VAR}/@{secretstore:key} references
+// that main substituted with sentinel placeholders before parsing, so that
+// migrations which round-trip a field's value through toml.Parse don't
+// accidentally bake in a resolved (or empty) value in place of the literal
+// reference.
+type MigrationContext struct {
+	placeholders map[string]string // placeholder -> original token
+}
+
+// NewMigrationContext creates a MigrationContext for the given placeholder
+// substitutions (placeholder text -> original $VAR/@{...} token).
+func NewMigrationContext(placeholders map[string]string) *MigrationContext {
+	return &MigrationContext{placeholders: placeholders}
+}
+
+// PreserveRef must be used whenever a migration synthesizes a new value to
+// replace an option's current one: it returns original unchanged if it was
+// a placeholder substituted for an env var or secret-store reference (so
+// the literal, unresolved reference survives the migration), and returns
+// replacement otherwise. Migrations must not synthesize values into fields
+// whose original text was a placeholder without going through this helper.
+func (c *MigrationContext) PreserveRef(original, replacement string) string {
+	if c == nil {
+		return replacement
+	}
+	if _, found := c.placeholders[original]; found {
+		return original
+	}
+	return replacement
+}
+
+// Placeholders returns the placeholder -> original token map, for callers
+// that need to restore references in already-rendered output (main does
+// this once migration is done).
+func (c *MigrationContext) Placeholders() map[string]string {
+	if c == nil {
+		return nil
+	}
+	return c.placeholders
+}
+
+// OptionMigrationFunc migrates a single deprecated option within a plugin's
+// TOML table. It receives the full table so it can inspect sibling options
+// (e.g. to avoid clobbering a new-style option that is already present) and
+// the run's MigrationContext so it can preserve $VAR/@{secretstore:key}
+// references. It modifies tbl in place and returns whether it actually
+// changed anything.
+type OptionMigrationFunc func(tbl *ast.Table, ctx *MigrationContext) (changed bool, err error)
+
+// Diagnostic is a non-fatal note emitted by a migration function, e.g. to
+// flag a field that was dropped because it has no new-style equivalent.
+type Diagnostic struct {
+	Severity string // e.g. "warning", "note"
+	Message  string
+}
+
+// MigrationResult is returned by PluginMigrationFuncV2: the migrated bytes
+// plus any diagnostics collected while producing them.
+type MigrationResult struct {
+	Content     []byte
+	Diagnostics []Diagnostic
+}
+
+// PluginMigrationFuncV2 is the richer successor to PluginMigrationFunc: in
+// addition to the migrated bytes it can report non-fatal diagnostics, e.g.
+// "field X was dropped because it has no equivalent", and it receives the
+// run's MigrationContext to preserve $VAR/@{secretstore:key} references.
+// New migrations should prefer this signature; PluginMigrationFunc keeps
+// working for existing registrations and is tried if no V2 migration is
+// registered for a plugin.
+type PluginMigrationFuncV2 func(tbl *ast.Table, ctx *MigrationContext) (MigrationResult, error)
+
 var PluginMigrations = make(map[string]PluginMigrationFunc)
 
+// PluginMigrationsV2 holds the same kind of registration as PluginMigrations
+// but for migrations using the MigrationResult/Diagnostic signature.
+var PluginMigrationsV2 = make(map[string]PluginMigrationFuncV2)
+
+// KnownPlugins maps a bare plugin name (e.g. "cpu") to the category it was
+// registered under (e.g. "inputs"), for names that are unambiguous across
+// categories. It is populated as a side effect of AddPluginMigration and
+// AddOptionMigration, which means importing "migrations/all" is enough to
+// build the registry used to recognize legacy, category-less plugin tables
+// such as `[cpu]` or `[[plugins.cpu]]`.
+var KnownPlugins = make(map[string]string)
+
+// AmbiguousPlugins holds bare plugin names registered under more than one
+// category (real Telegraf has same-named plugins in both inputs and
+// outputs, e.g. "file", "exec", "http"). Such a name is removed from
+// KnownPlugins rather than left pointing at whichever category happened to
+// register last, so callers can detect the collision and leave the legacy
+// bare table unrewritten instead of guessing.
+var AmbiguousPlugins = make(map[string]bool)
+
+// OptionMigrations holds option-level migrations keyed by "plugin/option",
+// e.g. "inputs.cpu/drop". Unlike PluginMigrations, these run for every
+// section regardless of whether a whole-plugin migration exists, so common
+// cross-plugin deprecations can be fixed without a dedicated plugin migration.
+var OptionMigrations = make(map[string]OptionMigrationFunc)
+
 func AddPluginMigration(name string, f PluginMigrationFunc) {
 	if _, found := PluginMigrations[name]; found {
 		panic(fmt.Errorf("plugin migration function already registered for %q", name))
 	}
 	PluginMigrations[name] = f
+	registerKnownPlugin(name)
+}
+
+// AddPluginMigrationV2 registers a migration using the MigrationResult-based
+// signature; see PluginMigrationFuncV2.
+func AddPluginMigrationV2(name string, f PluginMigrationFuncV2) {
+	if _, found := PluginMigrationsV2[name]; found {
+		panic(fmt.Errorf("plugin migration function already registered for %q", name))
+	}
+	PluginMigrationsV2[name] = f
+	registerKnownPlugin(name)
+}
+
+func registerKnownPlugin(name string) {
+	category, plugin, found := strings.Cut(name, ".")
+	if !found {
+		return
+	}
+	if AmbiguousPlugins[plugin] {
+		return
+	}
+	if existing, ok := KnownPlugins[plugin]; ok && existing != category {
+		delete(KnownPlugins, plugin)
+		AmbiguousPlugins[plugin] = true
+		return
+	}
+	KnownPlugins[plugin] = category
+}
+
+// AddOptionMigration registers a migration for a single option of the given
+// plugin. The plugin argument is the plugin's fully qualified section name
+// (e.g. "inputs.cpu") and option is the TOML key within that section. Option
+// migrations run before the plugin-level migration, for every section whose
+// name matches plugin, whether or not that plugin has a whole-plugin
+// migration registered. They modify the section's parsed table, which a
+// plugin-level migration then receives; they are not run on the output of a
+// plugin-level migration.
+func AddOptionMigration(plugin, option string, f OptionMigrationFunc) {
+	key := plugin + "/" + option
+	if _, found := OptionMigrations[key]; found {
+		panic(fmt.Errorf("option migration function already registered for %q", key))
+	}
+	OptionMigrations[key] = f
+	registerKnownPlugin(plugin)
 }
 
 func CreateTOMLStruct(category, plugin string) map[string]map[string][]interface{} {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/srebhan/test/migrations"
+)
+
+// refPattern matches the reference syntaxes Telegraf configs use for
+// deferred value substitution: $VAR, ${VAR} and @{secretstore:key}.
+var refPattern = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\}|\$[A-Za-z_][A-Za-z0-9_]*|@\{[^}]*\}`)
+
+// substituteRefs replaces every $VAR/${VAR}/@{secretstore:key} reference in
+// data with a sentinel placeholder, returning the substituted bytes along
+// with a map from placeholder to the original token. Migrations round-trip
+// field values through toml.Parse and CreateTOMLStruct, which would
+// otherwise resolve (or blank out) these references; substituting them with
+// inert placeholders before parsing keeps the literal reference intact
+// until restoreRefs puts it back in the emitted output.
+func substituteRefs(data []byte) ([]byte, map[string]string) {
+	placeholders := make(map[string]string)
+	n := 0
+	substituted := refPattern.ReplaceAllFunc(data, func(token []byte) []byte {
+		placeholder := fmt.Sprintf("__TGMIGRATE_REF_%d__", n)
+		n++
+		placeholders[placeholder] = string(token)
+		return []byte(placeholder)
+	})
+	return substituted, placeholders
+}
+
+// restoreRefs replaces every placeholder in data with the original token it
+// stands for. ctx may be nil, in which case data is returned unchanged.
+func restoreRefs(data []byte, ctx *migrations.MigrationContext) []byte {
+	if ctx == nil {
+		return data
+	}
+	for placeholder, original := range ctx.Placeholders() {
+		data = bytes.ReplaceAll(data, []byte(placeholder), []byte(original))
+	}
+	return data
+}
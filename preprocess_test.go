@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/srebhan/test/migrations"
+)
+
+func TestSubstituteRefsReplacesEachSyntax(t *testing.T) {
+	data := []byte(`password = "$MY_SECRET"
+token = "${MY_TOKEN}"
+apikey = "@{secretstore:api_key}"
+plain = "unchanged"
+`)
+
+	substituted, placeholders := substituteRefs(data)
+
+	if bytes.Contains(substituted, []byte("$MY_SECRET")) ||
+		bytes.Contains(substituted, []byte("${MY_TOKEN}")) ||
+		bytes.Contains(substituted, []byte("@{secretstore:api_key}")) {
+		t.Fatalf("substituteRefs left a reference unsubstituted: %s", substituted)
+	}
+	if !bytes.Contains(substituted, []byte(`plain = "unchanged"`)) {
+		t.Fatalf("substituteRefs touched a line with no reference: %s", substituted)
+	}
+	if len(placeholders) != 3 {
+		t.Fatalf("placeholders = %v, want 3 entries", placeholders)
+	}
+
+	want := map[string]bool{"$MY_SECRET": true, "${MY_TOKEN}": true, "@{secretstore:api_key}": true}
+	for _, original := range placeholders {
+		if !want[original] {
+			t.Errorf("unexpected original token %q in placeholders", original)
+		}
+		delete(want, original)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing originals in placeholders: %v", want)
+	}
+}
+
+func TestSubstituteRefsAssignsDistinctPlaceholders(t *testing.T) {
+	data := []byte(`a = "$FOO"
+b = "$FOO"
+`)
+	substituted, placeholders := substituteRefs(data)
+	if len(placeholders) != 2 {
+		t.Fatalf("placeholders = %v, want 2 distinct placeholders even for a repeated token", placeholders)
+	}
+	// Both occurrences still read as $FOO once restored.
+	ctx := migrations.NewMigrationContext(placeholders)
+	restored := restoreRefs(substituted, ctx)
+	if !bytes.Equal(restored, data) {
+		t.Errorf("restoreRefs(substituteRefs(data)) = %q, want %q", restored, data)
+	}
+}
+
+func TestRestoreRefsRoundTrips(t *testing.T) {
+	data := []byte(`password = "$MY_SECRET"
+token = "${MY_TOKEN}"
+apikey = "@{secretstore:api_key}"
+`)
+	substituted, placeholders := substituteRefs(data)
+	ctx := migrations.NewMigrationContext(placeholders)
+
+	restored := restoreRefs(substituted, ctx)
+	if !bytes.Equal(restored, data) {
+		t.Errorf("restoreRefs(substituteRefs(data)) = %q, want %q", restored, data)
+	}
+}
+
+func TestRestoreRefsNilContextReturnsInputUnchanged(t *testing.T) {
+	data := []byte("__TGMIGRATE_REF_0__")
+	if got := restoreRefs(data, nil); !bytes.Equal(got, data) {
+		t.Errorf("restoreRefs(data, nil) = %q, want %q unchanged", got, data)
+	}
+}
+
+func TestPreserveRefKeepsOriginalForPlaceholder(t *testing.T) {
+	placeholders := map[string]string{"__TGMIGRATE_REF_0__": "$MY_SECRET"}
+	ctx := migrations.NewMigrationContext(placeholders)
+
+	if got := ctx.PreserveRef("__TGMIGRATE_REF_0__", "resolved-value"); got != "__TGMIGRATE_REF_0__" {
+		t.Errorf("PreserveRef(placeholder, replacement) = %q, want the placeholder preserved", got)
+	}
+}
+
+func TestPreserveRefUsesReplacementForNonReference(t *testing.T) {
+	ctx := migrations.NewMigrationContext(map[string]string{})
+
+	if got := ctx.PreserveRef("plain-value", "replacement"); got != "replacement" {
+		t.Errorf("PreserveRef(non-ref, replacement) = %q, want %q", got, "replacement")
+	}
+}
+
+func TestPreserveRefNilContextUsesReplacement(t *testing.T) {
+	var ctx *migrations.MigrationContext
+	if got := ctx.PreserveRef("anything", "replacement"); got != "replacement" {
+		t.Errorf("nil.PreserveRef(...) = %q, want %q", got, "replacement")
+	}
+}
+
+func TestMigrationContextPlaceholdersNilContextReturnsNil(t *testing.T) {
+	var ctx *migrations.MigrationContext
+	if got := ctx.Placeholders(); got != nil {
+		t.Errorf("nil.Placeholders() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/srebhan/test/migrations"
+)
+
+// MigrationReport accumulates what happened to each section during a run so
+// it can be rendered for humans or for tooling, instead of interleaving
+// log.Printf calls with the rest of the program's output.
+type MigrationReport struct {
+	Entries []MigrationReportEntry
+}
+
+// MigrationReportEntry describes the outcome for a single section.
+type MigrationReportEntry struct {
+	File        string
+	Plugin      string
+	LineStart   int
+	LineEnd     int
+	Applied     bool
+	Diagnostics []migrations.Diagnostic
+	BytesBefore int
+	BytesAfter  int
+}
+
+func (r *MigrationReport) Add(e MigrationReportEntry) {
+	r.Entries = append(r.Entries, e)
+}
+
+// Render formats the report as "text", "json" or "sarif". An empty format
+// defaults to "text".
+func (r *MigrationReport) Render(format string) (string, error) {
+	switch format {
+	case "", "text":
+		return r.renderText(), nil
+	case "json":
+		return r.renderJSON()
+	case "sarif":
+		return r.renderSARIF()
+	default:
+		return "", fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+func (r *MigrationReport) renderText() string {
+	var out strings.Builder
+	for _, e := range r.Entries {
+		if !e.Applied && len(e.Diagnostics) == 0 {
+			continue
+		}
+
+		status := "unchanged"
+		if e.Applied {
+			status = "migrated"
+		}
+		fmt.Fprintf(&out, "%s:%d-%d: %s %s (%d -> %d bytes)\n", e.File, e.LineStart, e.LineEnd, e.Plugin, status, e.BytesBefore, e.BytesAfter)
+		for _, d := range e.Diagnostics {
+			fmt.Fprintf(&out, "  %s: %s\n", d.Severity, d.Message)
+		}
+	}
+	return out.String()
+}
+
+func (r *MigrationReport) renderJSON() (string, error) {
+	data, err := json.MarshalIndent(r.Entries, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// The sarif* types are a minimal subset of the SARIF 2.1.0 schema, just
+// enough to surface migration diagnostics as annotations in GitHub/GitLab
+// code-review UIs.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+func (r *MigrationReport) renderSARIF() (string, error) {
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "telegraf-migration"}}}
+	for _, e := range r.Entries {
+		loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: e.File},
+			Region:           sarifRegion{StartLine: e.LineStart, EndLine: e.LineEnd},
+		}}
+
+		if e.Applied {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    "plugin-migrated",
+				Level:     "note",
+				Message:   sarifMessage{Text: fmt.Sprintf("plugin %q was migrated", e.Plugin)},
+				Locations: []sarifLocation{loc},
+			})
+		}
+		for _, d := range e.Diagnostics {
+			level := d.Severity
+			if level == "" {
+				level = "warning"
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    "migration-diagnostic",
+				Level:     level,
+				Message:   sarifMessage{Text: d.Message},
+				Locations: []sarifLocation{loc},
+			})
+		}
+	}
+
+	log := sarifLog{
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/srebhan/test/migrations"
+)
+
+func TestMigrationReportRenderText(t *testing.T) {
+	var report MigrationReport
+	report.Add(MigrationReportEntry{
+		File: "a.conf", Plugin: "inputs.cpu", LineStart: 1, LineEnd: 3,
+		Applied: true, BytesBefore: 20, BytesAfter: 15,
+	})
+	report.Add(MigrationReportEntry{
+		File: "a.conf", Plugin: "inputs.mem", LineStart: 5, LineEnd: 6,
+		Diagnostics: []migrations.Diagnostic{{Severity: "warning", Message: "field dropped"}},
+	})
+	// Neither applied nor carrying diagnostics: must be skipped entirely.
+	report.Add(MigrationReportEntry{File: "a.conf", Plugin: "inputs.disk", LineStart: 8, LineEnd: 8})
+
+	got, err := report.Render("text")
+	if err != nil {
+		t.Fatalf("Render(text) failed: %v", err)
+	}
+	for _, want := range []string{
+		"a.conf:1-3: inputs.cpu migrated (20 -> 15 bytes)",
+		"a.conf:5-6: inputs.mem unchanged (0 -> 0 bytes)",
+		"warning: field dropped",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render(text) = %q, want substring %q", got, want)
+		}
+	}
+	if strings.Contains(got, "inputs.disk") {
+		t.Errorf("Render(text) = %q, want no entry for inputs.disk", got)
+	}
+}
+
+func TestMigrationReportRenderJSON(t *testing.T) {
+	var report MigrationReport
+	report.Add(MigrationReportEntry{File: "a.conf", Plugin: "inputs.cpu", LineStart: 1, LineEnd: 3, Applied: true})
+
+	got, err := report.Render("json")
+	if err != nil {
+		t.Fatalf("Render(json) failed: %v", err)
+	}
+	for _, want := range []string{`"File": "a.conf"`, `"Plugin": "inputs.cpu"`, `"Applied": true`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render(json) = %q, want substring %q", got, want)
+		}
+	}
+}
+
+func TestMigrationReportRenderJSONEmptyEntriesIsNull(t *testing.T) {
+	// A report with no entries marshals to the literal string "null", not
+	// "[]": callers that print one JSON report per file (reportCandidates)
+	// must be aware of this rather than assuming an empty array.
+	var report MigrationReport
+	got, err := report.Render("json")
+	if err != nil {
+		t.Fatalf("Render(json) failed: %v", err)
+	}
+	if strings.TrimSpace(got) != "null" {
+		t.Errorf("Render(json) of empty report = %q, want %q", got, "null")
+	}
+}
+
+func TestMigrationReportRenderSARIF(t *testing.T) {
+	var report MigrationReport
+	report.Add(MigrationReportEntry{
+		File: "a.conf", Plugin: "inputs.cpu", LineStart: 1, LineEnd: 3, Applied: true,
+		Diagnostics: []migrations.Diagnostic{{Severity: "warning", Message: "field dropped"}},
+	})
+
+	got, err := report.Render("sarif")
+	if err != nil {
+		t.Fatalf("Render(sarif) failed: %v", err)
+	}
+	for _, want := range []string{
+		`"ruleId": "plugin-migrated"`,
+		`"text": "plugin \"inputs.cpu\" was migrated"`,
+		`"ruleId": "migration-diagnostic"`,
+		`"level": "warning"`,
+		`"uri": "a.conf"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render(sarif) = %q, want substring %q", got, want)
+		}
+	}
+}
+
+func TestMigrationReportRenderSARIFDefaultsEmptySeverity(t *testing.T) {
+	var report MigrationReport
+	report.Add(MigrationReportEntry{
+		File: "a.conf", Plugin: "inputs.cpu", LineStart: 1, LineEnd: 1,
+		Diagnostics: []migrations.Diagnostic{{Message: "no severity given"}},
+	})
+
+	got, err := report.Render("sarif")
+	if err != nil {
+		t.Fatalf("Render(sarif) failed: %v", err)
+	}
+	if !strings.Contains(got, `"level": "warning"`) {
+		t.Errorf("Render(sarif) = %q, want default level %q", got, "warning")
+	}
+}
+
+func TestMigrationReportRenderUnknownFormat(t *testing.T) {
+	var report MigrationReport
+	if _, err := report.Render("yaml"); err == nil {
+		t.Fatal("Render(yaml) succeeded, want error for unknown format")
+	}
+}
+
+func TestMigrationReportRenderDefaultsToText(t *testing.T) {
+	var report MigrationReport
+	report.Add(MigrationReportEntry{File: "a.conf", Plugin: "inputs.cpu", LineStart: 1, LineEnd: 1, Applied: true})
+
+	got, err := report.Render("")
+	if err != nil {
+		t.Fatalf("Render(\"\") failed: %v", err)
+	}
+	want, err := report.Render("text")
+	if err != nil {
+		t.Fatalf("Render(text) failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("Render(\"\") = %q, want same as Render(text) = %q", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/influxdata/toml/ast"
+
+	"github.com/srebhan/test/migrations"
+)
+
+// withKnownPlugins swaps migrations.KnownPlugins/AmbiguousPlugins for the
+// duration of a test, restoring the originals on cleanup, since both are
+// mutated process-wide globals normally populated by migrations/all.
+func withKnownPlugins(t *testing.T, known map[string]string, ambiguous map[string]bool) {
+	t.Helper()
+	origKnown := migrations.KnownPlugins
+	origAmbiguous := migrations.AmbiguousPlugins
+	migrations.KnownPlugins = known
+	migrations.AmbiguousPlugins = ambiguous
+	t.Cleanup(func() {
+		migrations.KnownPlugins = origKnown
+		migrations.AmbiguousPlugins = origAmbiguous
+	})
+}
+
+func TestExplodeCategoryMultipleInstances(t *testing.T) {
+	root := &ast.Table{
+		Fields: map[string]interface{}{
+			"inputs": &ast.Table{
+				Fields: map[string]interface{}{
+					"cpu": []*ast.Table{
+						{Line: 2, Name: "cpu", Fields: map[string]interface{}{}},
+						{Line: 5, Name: "cpu", Fields: map[string]interface{}{}},
+					},
+				},
+			},
+		},
+	}
+
+	sections, err := splitToSections(root)
+	if err != nil {
+		t.Fatalf("splitToSections failed: %v", err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("sections = %+v, want 2", sections)
+	}
+	for _, s := range sections {
+		if s.name != "inputs.cpu" {
+			t.Errorf("section name = %q, want %q", s.name, "inputs.cpu")
+		}
+	}
+}
+
+func TestSplitToSectionsLegacyPluginsAlias(t *testing.T) {
+	root := &ast.Table{
+		Fields: map[string]interface{}{
+			"plugins": &ast.Table{
+				Fields: map[string]interface{}{
+					"cpu": []*ast.Table{
+						{Line: 2, Name: "cpu", Fields: map[string]interface{}{}},
+					},
+				},
+			},
+		},
+	}
+
+	sections, err := splitToSections(root)
+	if err != nil {
+		t.Fatalf("splitToSections failed: %v", err)
+	}
+	if len(sections) != 1 || sections[0].name != "inputs.cpu" {
+		t.Fatalf("sections = %+v, want single inputs.cpu", sections)
+	}
+}
+
+func TestSplitToSectionsPromotesKnownLegacyBareTable(t *testing.T) {
+	withKnownPlugins(t, map[string]string{"cpu": "inputs"}, map[string]bool{})
+
+	root := &ast.Table{
+		Fields: map[string]interface{}{
+			"cpu": &ast.Table{Line: 1, Name: "cpu", Fields: map[string]interface{}{}},
+		},
+	}
+
+	sections, err := splitToSections(root)
+	if err != nil {
+		t.Fatalf("splitToSections failed: %v", err)
+	}
+	if len(sections) != 1 || sections[0].name != "inputs.cpu" {
+		t.Fatalf("sections = %+v, want single inputs.cpu", sections)
+	}
+}
+
+func TestSplitToSectionsLeavesUnknownBareTableAlone(t *testing.T) {
+	withKnownPlugins(t, map[string]string{}, map[string]bool{})
+
+	root := &ast.Table{
+		Fields: map[string]interface{}{
+			"agent": &ast.Table{Line: 1, Name: "agent", Fields: map[string]interface{}{}},
+		},
+	}
+
+	sections, err := splitToSections(root)
+	if err != nil {
+		t.Fatalf("splitToSections failed: %v", err)
+	}
+	if len(sections) != 1 || sections[0].name != "agent" {
+		t.Fatalf("sections = %+v, want single unrewritten agent", sections)
+	}
+}
+
+func TestSplitToSectionsLeavesAmbiguousBareTableUnrewritten(t *testing.T) {
+	// Real Telegraf registers "http" under both inputs and outputs, so a
+	// legacy bare [http] table must not be guessed at.
+	withKnownPlugins(t, map[string]string{}, map[string]bool{"http": true})
+
+	root := &ast.Table{
+		Fields: map[string]interface{}{
+			"http": &ast.Table{Line: 1, Name: "http", Fields: map[string]interface{}{}},
+		},
+	}
+
+	sections, err := splitToSections(root)
+	if err != nil {
+		t.Fatalf("splitToSections failed: %v", err)
+	}
+	if len(sections) != 1 || sections[0].name != "http" {
+		t.Fatalf("sections = %+v, want single unrewritten http", sections)
+	}
+}
+
+func TestExplodeCategoryWrongShapeErrors(t *testing.T) {
+	root := &ast.Table{
+		Fields: map[string]interface{}{
+			"inputs": &ast.Table{
+				Fields: map[string]interface{}{
+					// A standalone nested sub-table with no array-of-tables
+					// wrapper, e.g. a file containing only
+					// [inputs.kafka_consumer.sasl] with no
+					// [[inputs.kafka_consumer]] header: valid TOML, wrong
+					// shape for explodeCategory.
+					"kafka_consumer": &ast.Table{Line: 1, Name: "kafka_consumer", Fields: map[string]interface{}{}},
+				},
+			},
+		},
+	}
+
+	if _, err := splitToSections(root); err == nil {
+		t.Fatal("splitToSections succeeded, want error for wrong AST shape")
+	}
+}
+
+func TestSplitToSectionsRejectsNonTableTopLevel(t *testing.T) {
+	root := &ast.Table{
+		Fields: map[string]interface{}{
+			"agent": "not a table",
+		},
+	}
+
+	if _, err := splitToSections(root); err == nil {
+		t.Fatal("splitToSections succeeded, want error for non-table field")
+	}
+}